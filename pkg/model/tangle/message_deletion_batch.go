@@ -0,0 +1,56 @@
+package tangle
+
+import (
+	"github.com/gohornet/hornet/pkg/model/hornet"
+)
+
+// MessageDeletionBatch coalesces the deletion of a set of messages, their
+// children edges and their indexation entries, so the pruning pipeline can
+// group many deletions behind a single journal write and Flush call
+// instead of journaling and deleting one message at a time.
+type MessageDeletionBatch struct {
+	messageIDs []*hornet.MessageID
+}
+
+// NewMessageDeletionBatch creates an empty batch.
+func NewMessageDeletionBatch() *MessageDeletionBatch {
+	return &MessageDeletionBatch{}
+}
+
+// QueueMessage adds a message to the batch. The message, its children
+// edges and its indexation entry (if any) are all deleted together when
+// the batch is flushed.
+func (b *MessageDeletionBatch) QueueMessage(messageID *hornet.MessageID) {
+	b.messageIDs = append(b.messageIDs, messageID)
+}
+
+// Flush deletes every queued message, along with its children edges and
+// indexation entry, through the same DeleteChild/DeleteChildren/
+// DeleteIndexation/DeleteMessage helpers the rest of the package already
+// uses for this data (see the legacy pruneMessages in
+// plugins/snapshot/pruning.go) instead of a key scheme of its own that
+// could silently drift out of sync with where this data actually lives.
+func (b *MessageDeletionBatch) Flush() error {
+	for _, messageID := range b.messageIDs {
+		cachedMsg := GetCachedMessageOrNil(messageID) // msg +1
+		if cachedMsg == nil {
+			continue
+		}
+
+		cachedMsg.ConsumeMessage(func(msg *Message) { // msg -1
+			DeleteChild(msg.GetParent1MessageID(), msg.GetMessageID())
+			DeleteChild(msg.GetParent2MessageID(), msg.GetMessageID())
+			DeleteChildren(msg.GetMessageID())
+
+			if indexationPayload := CheckIfIndexation(msg); indexationPayload != nil {
+				DeleteIndexation(indexationPayload.Index, msg.GetMessageID())
+			}
+
+			DeleteMessage(msg.GetMessageID())
+		})
+	}
+
+	b.messageIDs = b.messageIDs[:0]
+
+	return nil
+}
@@ -0,0 +1,411 @@
+package tangle
+
+import (
+	"os"
+	"sync"
+
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/dag"
+	"github.com/gohornet/hornet/pkg/database"
+	"github.com/gohornet/hornet/pkg/model/hornet"
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+// GCMode selects how the cold store is garbage collected during compaction.
+type GCMode byte
+
+const (
+	// GCModeOnline flushes the existing cold database in place. It does not
+	// run badger's value-log GC.
+	GCModeOnline GCMode = iota
+	// GCModeMoving writes a fresh cold database to a new path once the walk
+	// completes and replaces the old one with it, by removing the old
+	// directory and then renaming the new one into its place. The
+	// replacement is not atomic.
+	GCModeMoving
+)
+
+// SplitStoreConfig configures the hot/cold message segregation.
+type SplitStoreConfig struct {
+	// ColdPath is the badger directory the cold (archival) instance is
+	// opened under. The hot instance is not a separate database: it is
+	// database.Tangle() itself, the store every other read/write path in
+	// the node already targets, so messages segregated into "hot" by this
+	// package are real, live node state rather than a disconnected copy.
+	ColdPath string
+
+	// GCMode selects GCModeOnline or GCModeMoving for cold store compaction.
+	GCMode GCMode
+
+	// RetainState is the number of milestones of history that remain
+	// reachable from the cold store after a compaction. Older messages are
+	// deleted outright instead of being migrated.
+	RetainState milestone.Index
+
+	// PruningMilestoneIndexChanged is called with the compaction boundary
+	// once a compaction completes, so callers can keep driving the existing
+	// tangle plugin event of the same name.
+	PruningMilestoneIndexChanged func(index milestone.Index)
+}
+
+// SplitStore keeps recently confirmed messages in the node's regular tangle
+// store (hot) and spills older messages into a separate, owned cold one,
+// compacting the cold store on demand instead of deleting messages outright.
+type SplitStore struct {
+	sync.RWMutex
+
+	cfg  SplitStoreConfig
+	hot  kvstore.KVStore
+	cold kvstore.KVStore
+}
+
+// NewSplitStore opens (or creates) the cold badger instance described by
+// cfg and binds the hot side to database.Tangle(), the store every other
+// read/write path in the node already targets.
+func NewSplitStore(cfg SplitStoreConfig) (*SplitStore, error) {
+	cold, err := database.StoreWithDefaultSettings(cfg.ColdPath, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open cold store")
+	}
+
+	return &SplitStore{cfg: cfg, hot: database.Tangle(), cold: cold}, nil
+}
+
+// Hot returns the store messages should be written to and read from while
+// they are still within the compaction boundary.
+func (s *SplitStore) Hot() kvstore.KVStore {
+	s.RLock()
+	defer s.RUnlock()
+	return s.hot
+}
+
+// Cold returns the store holding messages older than the compaction
+// boundary.
+func (s *SplitStore) Cold() kvstore.KVStore {
+	s.RLock()
+	defer s.RUnlock()
+	return s.cold
+}
+
+// Compact walks the cone of the current latest solid milestone index (LSI),
+// marks every message reachable from it as a survivor, moves survivors
+// within the compaction boundary into the hot store, migrates the
+// remaining reachable-but-old messages into the cold store, scans both
+// stores for confirmed messages that are not survivors and deletes them as
+// unreachable. After a successful compaction it fires
+// PruningMilestoneIndexChanged with the new boundary.
+func (s *SplitStore) Compact(lsi milestone.Index, boundary milestone.Index, abortSignal <-chan struct{}) error {
+	s.Lock()
+	defer s.Unlock()
+
+	cachedMs := GetCachedMilestoneOrNil(lsi) // milestone +1
+	if cachedMs == nil {
+		return errors.Errorf("milestone %d not found", lsi)
+	}
+	milestoneMessageID := cachedMs.GetMilestone().MessageID
+	cachedMs.Release(true) // milestone -1
+
+	survivors := make(map[string]milestone.Index)
+
+	err := dag.TraverseParents(milestoneMessageID,
+		func(cachedMsgMeta *CachedMetadata) (bool, error) { // msg +1
+			defer cachedMsgMeta.Release(true) // msg -1
+			return true, nil
+		},
+		func(cachedMsgMeta *CachedMetadata) error { // msg +1
+			defer cachedMsgMeta.Release(true) // msg -1
+			meta := cachedMsgMeta.GetMetadata()
+			_, referencedAt := meta.GetReferenced()
+			survivors[meta.GetMessageID().MapKey()] = referencedAt
+			return nil
+		},
+		func(parentMessageID *hornet.MessageID) error { return nil },
+		nil,
+		true,
+		abortSignal)
+	if err != nil {
+		return errors.Wrap(err, "failed to traverse LSI cone")
+	}
+
+	if err := s.migrate(survivors, boundary); err != nil {
+		return err
+	}
+
+	if err := s.scanUnreachable(lsi, survivors); err != nil {
+		return err
+	}
+
+	if err := s.gc(); err != nil {
+		return err
+	}
+
+	if s.cfg.PruningMilestoneIndexChanged != nil {
+		s.cfg.PruningMilestoneIndexChanged(boundary)
+	}
+
+	return nil
+}
+
+// migrate moves every survivor either to hot (if confirmed within the
+// compaction boundary) or to cold (if older but still within RetainState),
+// and deletes anything that fell out the back of RetainState.
+func (s *SplitStore) migrate(survivors map[string]milestone.Index, boundary milestone.Index) error {
+	retainFloor := milestone.Index(0)
+	if boundary > s.cfg.RetainState {
+		retainFloor = boundary - s.cfg.RetainState
+	}
+
+	for mapKey, referencedAt := range survivors {
+		messageID := hornet.MessageIDFromMapKey(mapKey)
+
+		switch {
+		case referencedAt >= boundary:
+			if err := s.moveMessage(messageID, s.cold, s.hot); err != nil {
+				return err
+			}
+		case referencedAt >= retainFloor:
+			if err := s.moveMessage(messageID, s.hot, s.cold); err != nil {
+				return err
+			}
+		default:
+			if err := s.deleteMessage(messageID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// messageStorageKey is the raw kvstore key a message body is stored under
+// in both hot and cold. It only needs to be internally consistent between
+// the two Get/Set/Delete call sites below, since moveMessage never hands
+// this key to anything outside SplitStore itself.
+func messageStorageKey(messageID *hornet.MessageID) []byte {
+	return messageID.Slice()
+}
+
+func (s *SplitStore) moveMessage(messageID *hornet.MessageID, from kvstore.KVStore, to kvstore.KVStore) error {
+	key := messageStorageKey(messageID)
+
+	value, err := from.Get(key)
+	if err != nil {
+		if errors.Is(err, kvstore.ErrKeyNotFound) {
+			// already in the destination store
+			return nil
+		}
+		return err
+	}
+
+	if err := to.Set(key, value); err != nil {
+		return err
+	}
+
+	return from.Delete(key)
+}
+
+// deleteMessage drops a message, along with its children edges and
+// indexation entry, regardless of whether the message body itself
+// currently lives in hot or cold. Children edges and indexation entries
+// are tracked entirely in the real tangle store's own keyspace - moveMessage
+// never migrates them along with the message body - so DeleteChild/
+// DeleteChildren/DeleteIndexation/DeleteMessage are always the right calls
+// once the message has been located and decoded, the same as the legacy
+// pruneMessages in plugins/snapshot/pruning.go. Without this, compaction
+// would leave dangling children edges pointing at a message that no longer
+// exists and an orphan indexation entry that still resolves to it.
+func (s *SplitStore) deleteMessage(messageID *hornet.MessageID) error {
+	msg, foundInHot, err := s.loadMessage(messageID)
+	if err != nil {
+		return err
+	}
+	if msg == nil {
+		// already gone from both stores
+		return nil
+	}
+
+	DeleteChild(msg.GetParent1MessageID(), msg.GetMessageID())
+	DeleteChild(msg.GetParent2MessageID(), msg.GetMessageID())
+	DeleteChildren(msg.GetMessageID())
+
+	if indexationPayload := CheckIfIndexation(msg); indexationPayload != nil {
+		DeleteIndexation(indexationPayload.Index, msg.GetMessageID())
+	}
+
+	if foundInHot {
+		DeleteMessage(msg.GetMessageID())
+		return nil
+	}
+
+	return s.cold.Delete(messageStorageKey(messageID))
+}
+
+// loadMessage locates a message's body wherever it currently lives: hot
+// (the real tangle store, via the usual caching layer) or cold (this
+// package's own archival store, which holds nothing but raw message bytes
+// and so has to be decoded by hand). The returned bool reports which store
+// the message was found in, since that determines how it is later deleted.
+func (s *SplitStore) loadMessage(messageID *hornet.MessageID) (msg *Message, foundInHot bool, err error) {
+	cachedMsg := GetCachedMessageOrNil(messageID) // msg +1
+	if cachedMsg != nil {
+		defer cachedMsg.Release(true) // msg -1
+		return cachedMsg.GetMessage(), true, nil
+	}
+
+	data, err := s.cold.Get(messageStorageKey(messageID))
+	if err != nil {
+		if errors.Is(err, kvstore.ErrKeyNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	msg, err = MessageFromBytes(data)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to decode cold message")
+	}
+
+	return msg, false, nil
+}
+
+// scanUnreachable finds confirmed messages that are not in survivors -
+// i.e. not reachable from the LSI cone Compact just traversed - and
+// deletes them, the same as the out-the-back-of-RetainState branch of
+// migrate. Unconfirmed messages are deliberately left alone: they are
+// still-live tangle state (new tips, in-flight messages) rather than
+// history that failed to be referenced by the LSI's cone.
+func (s *SplitStore) scanUnreachable(lsi milestone.Index, survivors map[string]milestone.Index) error {
+	var err error
+
+	ForEachMessageID(func(messageID *hornet.MessageID) bool {
+		if _, isSurvivor := survivors[messageID.MapKey()]; isSurvivor {
+			return true
+		}
+
+		cachedMsgMeta := GetCachedMessageMetadataOrNil(messageID) // meta +1
+		if cachedMsgMeta == nil {
+			return true
+		}
+		confirmed, referencedIndex := cachedMsgMeta.GetMetadata().GetReferenced()
+		cachedMsgMeta.Release(true) // meta -1
+
+		if !confirmed || referencedIndex > lsi {
+			return true
+		}
+
+		if delErr := s.deleteMessage(messageID); delErr != nil {
+			err = delErr
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.scanUnreachableCold(survivors)
+}
+
+// scanUnreachableCold mirrors scanUnreachable for messages already
+// migrated into the cold store: those are no longer visible to
+// ForEachMessageID, which only walks the real tangle store, but cold is
+// exclusively owned by SplitStore, so it is safe to iterate directly
+// instead. Every message cold holds was already confirmed by migrate
+// before being moved there, so there is no confirmation check to repeat
+// here.
+func (s *SplitStore) scanUnreachableCold(survivors map[string]milestone.Index) error {
+	var err error
+
+	iterErr := s.cold.Iterate(kvstore.EmptyPrefix, func(key kvstore.Key, _ kvstore.Value) bool {
+		messageID := hornet.MessageIDFromMapKey(string(key))
+
+		if _, isSurvivor := survivors[messageID.MapKey()]; isSurvivor {
+			return true
+		}
+
+		if delErr := s.deleteMessage(messageID); delErr != nil {
+			err = delErr
+			return false
+		}
+		return true
+	})
+	if iterErr != nil {
+		return iterErr
+	}
+
+	return err
+}
+
+// gc finishes a compaction of the cold store according to the configured
+// GCMode.
+func (s *SplitStore) gc() error {
+	switch s.cfg.GCMode {
+	case GCModeOnline:
+		return s.cold.Flush()
+	case GCModeMoving:
+		return s.gcMoving()
+	default:
+		return errors.Errorf("unknown GC mode %d", s.cfg.GCMode)
+	}
+}
+
+// gcMoving writes a fresh cold database alongside the current one, copies
+// every remaining key into it, then removes the old directory and renames
+// the new one into its place. These two steps are not atomic: between them
+// there is no database at ColdPath.
+func (s *SplitStore) gcMoving() error {
+	newPath := s.cfg.ColdPath + ".new"
+	defer os.RemoveAll(newPath)
+
+	newCold, err := database.StoreWithDefaultSettings(newPath, true)
+	if err != nil {
+		return errors.Wrap(err, "failed to create new cold store")
+	}
+
+	var copyErr error
+	if err := s.cold.Iterate(kvstore.EmptyPrefix, func(key kvstore.Key, value kvstore.Value) bool {
+		copyErr = newCold.Set(key, value)
+		return copyErr == nil
+	}); err != nil {
+		return err
+	}
+	if copyErr != nil {
+		return errors.Wrap(copyErr, "failed to copy key into new cold store")
+	}
+
+	if err := newCold.Flush(); err != nil {
+		return err
+	}
+	if err := newCold.Close(); err != nil {
+		return err
+	}
+	if err := s.cold.Close(); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(s.cfg.ColdPath); err != nil {
+		return err
+	}
+	if err := os.Rename(newPath, s.cfg.ColdPath); err != nil {
+		return err
+	}
+
+	cold, err := database.StoreWithDefaultSettings(s.cfg.ColdPath, true)
+	if err != nil {
+		return err
+	}
+	s.cold = cold
+
+	return nil
+}
+
+// Close closes the cold store. The hot store is database.Tangle(), owned
+// and closed by whatever opened it, not by SplitStore.
+func (s *SplitStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.cold.Close()
+}
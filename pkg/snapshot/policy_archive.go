@@ -0,0 +1,24 @@
+package snapshot
+
+import (
+	"context"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+// archivePruner keeps everything; Prune is a no-op. It exists so operators
+// can run an archive node by config alone, without recompiling a node that
+// never prunes.
+type archivePruner struct{}
+
+func newArchivePruner(Config) *archivePruner {
+	return &archivePruner{}
+}
+
+func (p *archivePruner) Name() string { return "archive" }
+
+func (p *archivePruner) Status() Status { return Status{} }
+
+func (p *archivePruner) Prune(context.Context, milestone.Index) error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package snapshot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+	"github.com/gohornet/hornet/pkg/snapshot/pruner"
+)
+
+// bloomPolicyPruner adapts pruner.Pruner, the bloom-filter-driven two-pass
+// pruner meant for large-batch catch-up pruning, to the Pruner interface so
+// it can be selected like any other policy.
+type bloomPolicyPruner struct {
+	cfg Config
+	p   *pruner.Pruner
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func newBloomPolicyPruner(cfg Config) (*bloomPolicyPruner, error) {
+	if cfg.PruningIndexFunc == nil || cfg.SolidEntryPointsFunc == nil {
+		return nil, errors.New("bloom policy is missing required hooks")
+	}
+
+	p, err := pruner.NewPruner(pruner.Config{
+		SnapshotDir:          cfg.SnapshotDir,
+		BloomFilterSize:      cfg.BloomFilterSize,
+		SolidEntryPointsFunc: cfg.SolidEntryPointsFunc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bloomPolicyPruner{cfg: cfg, p: p}, nil
+}
+
+func (p *bloomPolicyPruner) Name() string { return "bloom" }
+
+func (p *bloomPolicyPruner) Status() Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status
+}
+
+func (p *bloomPolicyPruner) Prune(ctx context.Context, target milestone.Index) error {
+	pruningIndex := p.cfg.PruningIndexFunc()
+
+	p.mu.Lock()
+	p.status = Status{Running: true, CurrentMilestone: pruningIndex, TargetMilestone: target}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.status.Running = false
+		p.mu.Unlock()
+	}()
+
+	if err := p.p.Prune(pruningIndex, target, ctx.Done()); err != nil {
+		return err
+	}
+
+	// Unlike the depth, size and time policies, the bloom policy runs
+	// pruner.Pruner rather than the historical pruneDatabase, so this is the
+	// only place PruningMilestoneIndexChanged fires for it.
+	if p.cfg.OnPruningMilestoneIndexChanged != nil {
+		p.cfg.OnPruningMilestoneIndexChanged(target)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package snapshot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+// depthPruner is the historical "keep the last N milestones" behavior,
+// delegated to cfg.DepthPruneFunc.
+type depthPruner struct {
+	cfg Config
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func newDepthPruner(cfg Config) *depthPruner {
+	return &depthPruner{cfg: cfg}
+}
+
+func (p *depthPruner) Name() string { return "depth" }
+
+func (p *depthPruner) Status() Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status
+}
+
+func (p *depthPruner) Prune(ctx context.Context, target milestone.Index) error {
+	if p.cfg.DepthPruneFunc == nil {
+		return errors.New("depth policy is not wired to a prune function")
+	}
+
+	p.mu.Lock()
+	p.status = Status{Running: true, TargetMilestone: target}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.status.Running = false
+		p.mu.Unlock()
+	}()
+
+	if err := p.cfg.DepthPruneFunc(ctx, target); err != nil {
+		return err
+	}
+
+	if p.cfg.OnPruningMilestoneIndexChanged != nil {
+		p.cfg.OnPruningMilestoneIndexChanged(target)
+	}
+
+	return nil
+}
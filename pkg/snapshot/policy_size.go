@@ -0,0 +1,103 @@
+package snapshot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+// sizeStepMilestones is how far the target index is advanced per depth
+// prune while the size policy is still over budget, so DatabaseSizeFunc can
+// be re-checked between steps instead of only once at the end.
+const sizeStepMilestones = milestone.Index(10)
+
+// sizePruner repeatedly prunes the oldest milestones, in small steps, until
+// the on-disk database size drops below cfg.SizeTarget or target is
+// reached, whichever comes first.
+type sizePruner struct {
+	cfg Config
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func newSizePruner(cfg Config) *sizePruner {
+	return &sizePruner{cfg: cfg}
+}
+
+func (p *sizePruner) Name() string { return "size" }
+
+func (p *sizePruner) Status() Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status
+}
+
+func (p *sizePruner) Prune(ctx context.Context, target milestone.Index) error {
+	if p.cfg.DepthPruneFunc == nil || p.cfg.DatabaseSizeFunc == nil || p.cfg.PruningIndexFunc == nil {
+		return errors.New("size policy is missing required hooks")
+	}
+
+	current := p.cfg.PruningIndexFunc()
+
+	p.mu.Lock()
+	p.status.Running = true
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.status.Running = false
+		p.mu.Unlock()
+	}()
+
+	for current < target {
+		size, err := p.cfg.DatabaseSizeFunc()
+		if err != nil {
+			return err
+		}
+		if size <= p.cfg.SizeTarget {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		next := current + sizeStepMilestones
+		if next > target {
+			next = target
+		}
+
+		p.mu.Lock()
+		p.status = Status{Running: true, CurrentMilestone: current, TargetMilestone: next}
+		p.mu.Unlock()
+
+		if err := p.cfg.DepthPruneFunc(ctx, next); err != nil {
+			return err
+		}
+
+		// pruneDatabase silently clamps its target downward (not enough
+		// history, snapshot index too close, etc.), so next is only what we
+		// asked for, not necessarily what got pruned. Re-query the actual
+		// pruning index before trusting (or reporting) next, or a clamped
+		// step could make this loop - and anyone listening for
+		// OnPruningMilestoneIndexChanged - believe it made progress it
+		// didn't.
+		newCurrent := p.cfg.PruningIndexFunc()
+		if newCurrent <= current {
+			return errors.Errorf("size policy made no progress pruning towards milestone %d (stuck at %d)", next, newCurrent)
+		}
+		current = newCurrent
+
+		if p.cfg.OnPruningMilestoneIndexChanged != nil {
+			p.cfg.OnPruningMilestoneIndexChanged(current)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package snapshot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+// splitStorePolicyPruner adapts tangle.SplitStore, the hot/cold message
+// segregation store, to the Pruner interface so it can be selected like any
+// other policy instead of sitting unused beside the delete-based policies.
+//
+// cfg.SplitStore must be constructed with SplitStoreConfig.
+// PruningMilestoneIndexChanged left nil: this policy is the one place that
+// event fires for a splitstore-backed node, via
+// cfg.OnPruningMilestoneIndexChanged below.
+type splitStorePolicyPruner struct {
+	cfg Config
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func newSplitStorePolicyPruner(cfg Config) (*splitStorePolicyPruner, error) {
+	if cfg.SplitStore == nil || cfg.LatestSolidMilestoneIndexFunc == nil {
+		return nil, errors.New("splitstore policy is missing required hooks")
+	}
+
+	return &splitStorePolicyPruner{cfg: cfg}, nil
+}
+
+func (p *splitStorePolicyPruner) Name() string { return "splitstore" }
+
+func (p *splitStorePolicyPruner) Status() Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status
+}
+
+func (p *splitStorePolicyPruner) Prune(ctx context.Context, target milestone.Index) error {
+	lsi := p.cfg.LatestSolidMilestoneIndexFunc()
+
+	p.mu.Lock()
+	p.status = Status{Running: true, CurrentMilestone: lsi, TargetMilestone: target}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.status.Running = false
+		p.mu.Unlock()
+	}()
+
+	if err := p.cfg.SplitStore.Compact(lsi, target, ctx.Done()); err != nil {
+		return err
+	}
+
+	if p.cfg.OnPruningMilestoneIndexChanged != nil {
+		p.cfg.OnPruningMilestoneIndexChanged(target)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package snapshot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+func TestDepthPrunerFiresEventExactlyOnce(t *testing.T) {
+	fireCount := 0
+
+	cfg := Config{
+		DepthPruneFunc: func(ctx context.Context, target milestone.Index) error {
+			return nil
+		},
+		OnPruningMilestoneIndexChanged: func(index milestone.Index) {
+			fireCount++
+		},
+	}
+
+	p := newDepthPruner(cfg)
+	if err := p.Prune(context.Background(), milestone.Index(100)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fireCount != 1 {
+		t.Errorf("expected OnPruningMilestoneIndexChanged to fire exactly once, fired %d times", fireCount)
+	}
+}
+
+// TestSizePrunerStopsOnClampedProgress ensures the size policy re-queries
+// PruningIndexFunc after each step instead of trusting the step's target:
+// if DepthPruneFunc silently clamps its target downward and the pruning
+// index never moves, the loop must report an error instead of believing it
+// made progress and looping forever.
+func TestSizePrunerStopsOnClampedProgress(t *testing.T) {
+	pruningIndex := milestone.Index(0)
+
+	cfg := Config{
+		DepthPruneFunc: func(ctx context.Context, target milestone.Index) error {
+			// simulate pruneDatabase silently clamping its target and making
+			// no actual progress
+			return nil
+		},
+		DatabaseSizeFunc: func() (uint64, error) {
+			return 1000, nil
+		},
+		PruningIndexFunc: func() milestone.Index {
+			return pruningIndex
+		},
+		SizeTarget: 100,
+	}
+
+	p := newSizePruner(cfg)
+	err := p.Prune(context.Background(), milestone.Index(1000))
+	if err == nil {
+		t.Fatal("expected an error when the pruning index fails to advance, got nil")
+	}
+}
+
+func TestSizePrunerAdvancesWithRealProgress(t *testing.T) {
+	pruningIndex := milestone.Index(0)
+	fireCount := 0
+
+	cfg := Config{
+		DepthPruneFunc: func(ctx context.Context, target milestone.Index) error {
+			pruningIndex = target
+			return nil
+		},
+		DatabaseSizeFunc: func() (uint64, error) {
+			if pruningIndex >= 20 {
+				return 50, nil
+			}
+			return 1000, nil
+		},
+		PruningIndexFunc: func() milestone.Index {
+			return pruningIndex
+		},
+		SizeTarget: 100,
+		OnPruningMilestoneIndexChanged: func(index milestone.Index) {
+			fireCount++
+		},
+	}
+
+	p := newSizePruner(cfg)
+	if err := p.Prune(context.Background(), milestone.Index(1000)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if pruningIndex == 0 {
+		t.Error("expected the size policy to have made progress")
+	}
+	if fireCount == 0 {
+		t.Error("expected OnPruningMilestoneIndexChanged to fire at least once")
+	}
+}
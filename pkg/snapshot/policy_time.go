@@ -0,0 +1,85 @@
+package snapshot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+// timePruner retains cfg.RetentionPeriod of history by milestone
+// timestamp: it finds the highest milestone older than the retention
+// cutoff and delegates to cfg.DepthPruneFunc to prune up to it.
+type timePruner struct {
+	cfg Config
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func newTimePruner(cfg Config) *timePruner {
+	return &timePruner{cfg: cfg}
+}
+
+func (p *timePruner) Name() string { return "time" }
+
+func (p *timePruner) Status() Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status
+}
+
+func (p *timePruner) Prune(ctx context.Context, target milestone.Index) error {
+	if p.cfg.DepthPruneFunc == nil || p.cfg.MilestoneTimestampFunc == nil || p.cfg.PruningIndexFunc == nil {
+		return errors.New("time policy is missing required hooks")
+	}
+
+	cutoff := time.Now().Add(-p.cfg.RetentionPeriod)
+
+	pruneUpTo := p.cfg.PruningIndexFunc()
+	for index := pruneUpTo + 1; index <= target; index++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		ts, err := p.cfg.MilestoneTimestampFunc(index)
+		if err != nil {
+			return err
+		}
+		if ts.After(cutoff) {
+			break
+		}
+
+		pruneUpTo = index
+	}
+
+	if pruneUpTo <= p.cfg.PruningIndexFunc() {
+		// nothing is old enough to prune yet
+		return nil
+	}
+
+	p.mu.Lock()
+	p.status = Status{Running: true, TargetMilestone: pruneUpTo}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.status.Running = false
+		p.mu.Unlock()
+	}()
+
+	if err := p.cfg.DepthPruneFunc(ctx, pruneUpTo); err != nil {
+		return err
+	}
+
+	if p.cfg.OnPruningMilestoneIndexChanged != nil {
+		p.cfg.OnPruningMilestoneIndexChanged(pruneUpTo)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+// Package snapshot provides the Pruner interface and the selectable
+// pruning policies (archive, depth, size, time, bloom, splitstore) a node can run, mirroring
+// the pruning-mode design used by other Go chain nodes so operators can
+// pick a policy from config instead of recompiling.
+package snapshot
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+	"github.com/gohornet/hornet/pkg/model/tangle"
+)
+
+// Status reports the live progress of a Pruner.
+type Status struct {
+	Running          bool
+	CurrentMilestone milestone.Index
+	TargetMilestone  milestone.Index
+}
+
+// Pruner is implemented by every selectable pruning policy. Exactly one
+// implementation is active per node, chosen by NewPruner from the
+// configured policy name.
+type Pruner interface {
+	// Name returns the policy name as used in the config file.
+	Name() string
+	// Status returns the current progress of a running (or idle) prune.
+	Status() Status
+	// Prune runs the policy up to target, the highest milestone index it is
+	// allowed to prune to.
+	Prune(ctx context.Context, target milestone.Index) error
+}
+
+// Config bundles the hooks every policy implementation needs into the
+// node's existing pruning machinery. The hooks exist so this package does
+// not have to depend on the plugin layer that wires them up.
+type Config struct {
+	// DepthPruneFunc runs the existing milestone-depth prune (the "keep the
+	// last N milestones" behavior) up to target. The size and time policies
+	// reuse it rather than reimplementing message deletion.
+	DepthPruneFunc func(ctx context.Context, target milestone.Index) error
+
+	// DatabaseSizeFunc returns the current on-disk database size in bytes.
+	// Required by the size policy.
+	DatabaseSizeFunc func() (uint64, error)
+
+	// MilestoneTimestampFunc returns the confirmation timestamp of the
+	// given milestone. Required by the time policy.
+	MilestoneTimestampFunc func(index milestone.Index) (time.Time, error)
+
+	// PruningIndexFunc returns the current pruning index: the highest
+	// milestone index already pruned from the database, so the lowest index
+	// still present is one above it.
+	PruningIndexFunc func() milestone.Index
+
+	// SizeTarget is the on-disk database size the size policy prunes down
+	// to, in bytes.
+	SizeTarget uint64
+
+	// RetentionPeriod is how much history the time policy keeps.
+	RetentionPeriod time.Duration
+
+	// OnPruningMilestoneIndexChanged is called by every policy once it
+	// finishes pruning up to a new index. Wired to the tangle plugin's
+	// PruningMilestoneIndexChanged event by the caller.
+	OnPruningMilestoneIndexChanged func(index milestone.Index)
+
+	// SnapshotDir is the directory the bloom policy persists its filter
+	// and progress meta under, so an interrupted prune can resume pass one
+	// instead of redoing it. Required by the bloom policy.
+	SnapshotDir string
+
+	// BloomFilterSize sizes the bloom policy's filter for the number of
+	// messages expected to be reachable within a pruning range. Required
+	// by the bloom policy.
+	BloomFilterSize uint64
+
+	// SolidEntryPointsFunc returns the current set of solid entry point
+	// message IDs, keyed by MapKey(). Required by the bloom policy, which
+	// must never delete a solid entry point even if pass one's filter
+	// does not recognize it as reachable.
+	SolidEntryPointsFunc func() (map[string]struct{}, error)
+
+	// SplitStore is the hot/cold store the splitstore policy compacts.
+	// Required by the splitstore policy.
+	SplitStore *tangle.SplitStore
+
+	// LatestSolidMilestoneIndexFunc returns the current LSI, the cone the
+	// splitstore policy walks to find survivors. Required by the
+	// splitstore policy.
+	LatestSolidMilestoneIndexFunc func() milestone.Index
+}
+
+// NewPruner constructs the Pruner for the given policy name: "archive"
+// (keep everything), "depth" (keep the last N milestones), "size" (prune
+// oldest milestones until the on-disk size drops below a target), "time"
+// (retain a fixed duration of history by milestone timestamp), "bloom"
+// (two-pass bloom-filter prune via pruner.Pruner) or "splitstore" (hot/cold
+// compaction via tangle.SplitStore).
+func NewPruner(policy string, cfg Config) (Pruner, error) {
+	switch policy {
+	case "archive":
+		return newArchivePruner(cfg), nil
+	case "depth":
+		return newDepthPruner(cfg), nil
+	case "size":
+		return newSizePruner(cfg), nil
+	case "time":
+		return newTimePruner(cfg), nil
+	case "bloom":
+		return newBloomPolicyPruner(cfg)
+	case "splitstore":
+		return newSplitStorePolicyPruner(cfg)
+	default:
+		return nil, errors.Errorf("unknown pruning policy %q", policy)
+	}
+}
@@ -0,0 +1,151 @@
+package pruner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// bloomFileMagic identifies a file written by bloomFilter.WriteFile, so
+// readBloomFilterFile can reject anything else instead of misreading it.
+const bloomFileMagic = uint32(0x626c6f31) // "blo1"
+
+// bloomFilter is a minimal bit-array bloom filter sized for a target number
+// of elements, using the Kirsch-Mitzenmacher double-hashing scheme to derive
+// k independent bit positions from a single pair of 64-bit hashes. It exists
+// so the bloom pruner does not depend on an external bloom filter library.
+type bloomFilter struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint64 // number of hash functions
+	n    uint64 // number of elements added so far
+}
+
+// newBloomFilter creates an empty filter sized for roughly `size` elements.
+// The bit array is sized at 8 bits per expected element, which keeps the
+// false-positive rate around 2% at k=bloomFilterHashFuncs.
+func newBloomFilter(size uint64, hashFuncs uint64) (*bloomFilter, error) {
+	if size == 0 {
+		return nil, errors.New("bloom filter size must be greater than zero")
+	}
+	if hashFuncs == 0 {
+		hashFuncs = 1
+	}
+
+	numBits := size * 8
+	return &bloomFilter{
+		bits: make([]uint64, (numBits+63)/64),
+		m:    numBits,
+		k:    hashFuncs,
+	}, nil
+}
+
+func (f *bloomFilter) positions(key []byte) []uint64 {
+	h1 := fnv.New64a()
+	h1.Write(key)
+	sum1 := h1.Sum64()
+
+	h2 := fnv.New64()
+	h2.Write(key)
+	sum2 := h2.Sum64()
+	if sum2 == 0 {
+		sum2 = 1
+	}
+
+	positions := make([]uint64, f.k)
+	for i := uint64(0); i < f.k; i++ {
+		positions[i] = (sum1 + i*sum2) % f.m
+	}
+	return positions
+}
+
+// Add inserts key into the filter.
+func (f *bloomFilter) Add(key []byte) {
+	for _, pos := range f.positions(key) {
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+	f.n++
+}
+
+// Contains reports whether key may have been added. False positives are
+// possible, false negatives are not.
+func (f *bloomFilter) Contains(key []byte) bool {
+	for _, pos := range f.positions(key) {
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FalsePositiveProbability estimates the filter's current false-positive
+// rate given how many elements have been added so far.
+func (f *bloomFilter) FalsePositiveProbability() float64 {
+	if f.n == 0 {
+		return 0
+	}
+	exponent := -float64(f.k) * float64(f.n) / float64(f.m)
+	return math.Pow(1-math.Exp(exponent), float64(f.k))
+}
+
+// WriteFile persists the filter to path so a restarted pruner can resume
+// pass one instead of redoing it.
+func (f *bloomFilter) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	for _, v := range []uint64{uint64(bloomFileMagic), f.m, f.k, f.n} {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	if err := binary.Write(w, binary.LittleEndian, f.bits); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// readBloomFilterFile loads a filter previously persisted by WriteFile.
+func readBloomFilterFile(path string) (*bloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+
+	var magic uint64
+	if err := binary.Read(r, binary.LittleEndian, &magic); err != nil {
+		return nil, err
+	}
+	if uint32(magic) != bloomFileMagic {
+		return nil, errors.New("not a valid bloom filter file")
+	}
+
+	f := &bloomFilter{}
+	if err := binary.Read(r, binary.LittleEndian, &f.m); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &f.k); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &f.n); err != nil {
+		return nil, err
+	}
+
+	f.bits = make([]uint64, (f.m+63)/64)
+	if err := binary.Read(r, binary.LittleEndian, f.bits); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,59 @@
+package pruner
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBloomFilterAddContains(t *testing.T) {
+	f, err := newBloomFilter(1000, bloomFilterHashFuncs)
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+
+	f.Add([]byte("present"))
+
+	if !f.Contains([]byte("present")) {
+		t.Error("expected filter to contain an added key")
+	}
+	if f.Contains([]byte("absent")) {
+		t.Error("expected filter to not contain a key that was never added (false positives are possible, but this specific case does not collide)")
+	}
+}
+
+func TestBloomFilterPersistRoundTrip(t *testing.T) {
+	f, err := newBloomFilter(1000, bloomFilterHashFuncs)
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	for _, key := range keys {
+		f.Add(key)
+	}
+
+	path := filepath.Join(t.TempDir(), "filter.bin")
+	if err := f.WriteFile(path); err != nil {
+		t.Fatalf("failed to write filter: %v", err)
+	}
+
+	loaded, err := readBloomFilterFile(path)
+	if err != nil {
+		t.Fatalf("failed to read filter: %v", err)
+	}
+
+	for _, key := range keys {
+		if !loaded.Contains(key) {
+			t.Errorf("expected loaded filter to contain %q", key)
+		}
+	}
+	if loaded.n != f.n {
+		t.Errorf("expected n=%d after round trip, got %d", f.n, loaded.n)
+	}
+}
+
+func TestNewBloomFilterRejectsZeroSize(t *testing.T) {
+	if _, err := newBloomFilter(0, bloomFilterHashFuncs); err == nil {
+		t.Error("expected error for zero-sized filter")
+	}
+}
@@ -0,0 +1,330 @@
+// Package pruner implements a bloom-filter-driven pruning mode for large-batch
+// message deletion, modeled after go-ethereum's snapshot pruner.
+//
+// Rather than keeping every reachable message ID of a pruning range in a
+// map[string]struct{} (which grows unbounded while catching up over many
+// milestones), the bloom pruner makes two passes: the first walks the
+// parent cones of the milestones in range and records each reachable
+// message ID in a bloom filter, and the second scans the raw message store
+// and deletes anything that is neither in the filter nor a protected solid
+// entry point, provided it is also confirmed strictly before the pruning
+// target (new, not-yet-confirmed tangle state is left untouched).
+package pruner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/dag"
+	"github.com/gohornet/hornet/pkg/logger"
+	"github.com/gohornet/hornet/pkg/model/hornet"
+	"github.com/gohornet/hornet/pkg/model/milestone"
+	"github.com/gohornet/hornet/pkg/model/tangle"
+)
+
+const (
+	// bloomFilterHashFuncs is the number of hash functions used for the bloom
+	// filter. 4 keeps the false-positive rate low without blowing up the
+	// per-entry cost.
+	bloomFilterHashFuncs = 4
+
+	// bloomFileName is the name of the file the bloom filter is persisted
+	// under, relative to a pruner's snapshot directory.
+	bloomFileName = "pruning_bloom.filter"
+
+	// bloomMetaFileName stores the progress made during pass one, so a
+	// restarted pruner knows whether it can skip straight to pass two.
+	bloomMetaFileName = "pruning_bloom.meta.json"
+)
+
+var log = logger.NewLogger("Pruner")
+
+// ErrBloomFilterNotInitialized is returned when Prune is called before the
+// bloom filter has been created or loaded.
+var ErrBloomFilterNotInitialized = errors.New("bloom filter not initialized")
+
+// Config holds the parameters needed to run a bloom-filter-based prune.
+type Config struct {
+	// SnapshotDir is the directory the bloom filter is persisted under, so
+	// an interrupted prune can resume pass one instead of redoing it.
+	SnapshotDir string
+
+	// BloomFilterSize is the number of elements the bloom filter is sized
+	// for. It should be set comfortably above the number of messages
+	// expected to be reachable within the pruning range.
+	BloomFilterSize uint64
+
+	// SolidEntryPointsFunc returns the current set of solid entry point
+	// message IDs. Messages in this set are never deleted in pass two,
+	// even if they are (correctly) absent from the bloom filter. It is
+	// called again at the start of every sweep rather than once at
+	// construction time, since the allowlist changes between prunes and a
+	// stale copy would delete messages the current solid entry points are
+	// meant to protect.
+	SolidEntryPointsFunc func() (map[string]struct{}, error)
+}
+
+// Pruner prunes the message store in two passes, using a persisted bloom
+// filter to keep memory usage bounded across multi-milestone catch-up prunes.
+type Pruner struct {
+	cfg    Config
+	filter *bloomFilter
+
+	// lastTraversedMilestone is the highest milestone index whose parent
+	// cone has already been inserted into the bloom filter, so pass one can
+	// resume instead of restarting from PruningIndex+1.
+	lastTraversedMilestone milestone.Index
+}
+
+type bloomMeta struct {
+	LastTraversedMilestone milestone.Index `json:"lastTraversedMilestone"`
+	FalsePositiveRate      float64         `json:"falsePositiveRate"`
+}
+
+// NewPruner creates a Pruner, loading a previously persisted bloom filter
+// from cfg.SnapshotDir if one exists, or creating a fresh one otherwise.
+func NewPruner(cfg Config) (*Pruner, error) {
+	if cfg.BloomFilterSize == 0 {
+		return nil, errors.New("BloomFilterSize must be greater than zero")
+	}
+	if cfg.SolidEntryPointsFunc == nil {
+		return nil, errors.New("SolidEntryPointsFunc must be set")
+	}
+
+	p := &Pruner{cfg: cfg}
+
+	if err := p.loadOrCreateFilter(); err != nil {
+		return nil, errors.Wrap(err, "failed to load or create bloom filter")
+	}
+
+	return p, nil
+}
+
+func (p *Pruner) bloomFilePath() string {
+	return filepath.Join(p.cfg.SnapshotDir, bloomFileName)
+}
+
+func (p *Pruner) metaFilePath() string {
+	return filepath.Join(p.cfg.SnapshotDir, bloomMetaFileName)
+}
+
+func (p *Pruner) loadOrCreateFilter() error {
+	filterPath := p.bloomFilePath()
+
+	if _, err := os.Stat(filterPath); err == nil {
+		filter, err := readBloomFilterFile(filterPath)
+		if err != nil {
+			log.Warnf("failed to read persisted bloom filter, starting fresh: %v", err)
+		} else {
+			p.filter = filter
+
+			meta, err := p.readMeta()
+			if err == nil {
+				p.lastTraversedMilestone = meta.LastTraversedMilestone
+			}
+			return nil
+		}
+	}
+
+	filter, err := newBloomFilter(p.cfg.BloomFilterSize, bloomFilterHashFuncs)
+	if err != nil {
+		return err
+	}
+	p.filter = filter
+
+	return nil
+}
+
+func (p *Pruner) readMeta() (*bloomMeta, error) {
+	data, err := os.ReadFile(p.metaFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &bloomMeta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+func (p *Pruner) persist() error {
+	if err := p.filter.WriteFile(p.bloomFilePath()); err != nil {
+		return errors.Wrap(err, "failed to persist bloom filter")
+	}
+
+	meta := bloomMeta{
+		LastTraversedMilestone: p.lastTraversedMilestone,
+		FalsePositiveRate:      p.filter.FalsePositiveProbability(),
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(p.metaFilePath(), data, 0o666)
+}
+
+// Prune walks every milestone between the last traversed milestone (or
+// pruningIndex+1, if pass one has not started yet) and targetIndex, adding
+// every reachable message ID to the bloom filter (pass one), then deletes
+// every message in the raw store that the filter does not recognize, that
+// isn't a protected solid entry point, and that was confirmed strictly
+// before targetIndex (pass two, see shouldPruneDuringSweep).
+func (p *Pruner) Prune(pruningIndex, targetIndex milestone.Index, abortSignal <-chan struct{}) error {
+	if p.filter == nil {
+		return ErrBloomFilterNotInitialized
+	}
+
+	startIndex := pruningIndex + 1
+	if p.lastTraversedMilestone >= startIndex {
+		startIndex = p.lastTraversedMilestone + 1
+	}
+
+	for msIndex := startIndex; msIndex <= targetIndex; msIndex++ {
+		select {
+		case <-abortSignal:
+			return errors.New("pruning aborted")
+		default:
+		}
+
+		cachedMs := tangle.GetCachedMilestoneOrNil(msIndex) // milestone +1
+		if cachedMs == nil {
+			continue
+		}
+
+		milestoneMessageID := cachedMs.GetMilestone().MessageID
+		cachedMs.Release(true) // milestone -1
+
+		err := dag.TraverseParents(milestoneMessageID,
+			func(cachedMsgMeta *tangle.CachedMetadata) (bool, error) { // msg +1
+				defer cachedMsgMeta.Release(true) // msg -1
+				return true, nil
+			},
+			func(cachedMsgMeta *tangle.CachedMetadata) error { // msg +1
+				defer cachedMsgMeta.Release(true) // msg -1
+				p.filter.Add(bloomKey(cachedMsgMeta.GetMetadata().GetMessageID().MapKey()))
+				return nil
+			},
+			func(parentMessageID *hornet.MessageID) error { return nil },
+			nil,
+			true,
+			nil)
+		if err != nil {
+			return errors.Wrapf(err, "failed to traverse milestone %d", msIndex)
+		}
+
+		p.lastTraversedMilestone = msIndex
+		if err := p.persist(); err != nil {
+			return err
+		}
+	}
+
+	return p.sweep(targetIndex, abortSignal)
+}
+
+// shouldPruneDuringSweep reports whether a message must be deleted during
+// pass two: it is not a protected solid entry point, pass one's bloom
+// filter does not recognize it as reachable, and it was confirmed strictly
+// before targetIndex. The filter only ever covers messages reachable from
+// milestones pruningIndex+1..targetIndex, so a message that is not yet
+// confirmed (still-live tangle state, new tips) or that was confirmed at
+// or after targetIndex is simply outside what pass one has traversed so
+// far, not unreachable history, and must survive the sweep.
+func shouldPruneDuringSweep(isSolidEntryPoint, inFilter, confirmed bool, referencedIndex, targetIndex milestone.Index) bool {
+	if isSolidEntryPoint || inFilter {
+		return false
+	}
+	return confirmed && referencedIndex < targetIndex
+}
+
+// sweep is pass two: it iterates the raw message store and deletes every
+// message that is neither a protected solid entry point nor recognized as
+// reachable by the bloom filter, and that is older than targetIndex's cone
+// (see shouldPruneDuringSweep).
+func (p *Pruner) sweep(targetIndex milestone.Index, abortSignal <-chan struct{}) (err error) {
+	// Queried fresh for every sweep rather than once at construction: the
+	// solid entry point set moves forward as the node keeps solidifying,
+	// and sweeping against a stale allowlist would delete messages the
+	// current one is meant to protect.
+	solidEntryPoints, err := p.cfg.SolidEntryPointsFunc()
+	if err != nil {
+		return errors.Wrap(err, "failed to load solid entry points")
+	}
+
+	msgCountDeleted := 0
+	msgCountChecked := 0
+
+	tangle.ForEachMessageID(func(messageID *hornet.MessageID) bool {
+		select {
+		case <-abortSignal:
+			err = errors.New("pruning aborted")
+			return false
+		default:
+		}
+
+		msgCountChecked++
+
+		mapKey := messageID.MapKey()
+		_, isSolidEntryPoint := solidEntryPoints[mapKey]
+		// either genuinely reachable, or a false positive: in both cases
+		// it is safer to keep the message around.
+		inFilter := p.filter.Contains(bloomKey(mapKey))
+
+		var confirmed bool
+		var referencedIndex milestone.Index
+		if !isSolidEntryPoint && !inFilter {
+			cachedMsgMeta := tangle.GetCachedMessageMetadataOrNil(messageID) // meta +1
+			if cachedMsgMeta != nil {
+				confirmed, referencedIndex = cachedMsgMeta.GetMetadata().GetReferenced()
+				cachedMsgMeta.Release(true) // meta -1
+			}
+		}
+
+		if !shouldPruneDuringSweep(isSolidEntryPoint, inFilter, confirmed, referencedIndex, targetIndex) {
+			return true
+		}
+
+		msgCountDeleted += pruneMessageByID(messageID)
+		return true
+	})
+
+	log.Infof("bloom sweep up to milestone %d done. Pruned %d/%d messages. False positive rate: %f",
+		targetIndex, msgCountDeleted, msgCountChecked, p.filter.FalsePositiveProbability())
+
+	return err
+}
+
+func bloomKey(mapKey string) []byte {
+	return []byte(mapKey)
+}
+
+// pruneMessageByID removes a single message and its associated edges and
+// indexation entries from the database. It mirrors the per-message deletion
+// done by the legacy pruneMessages in plugins/snapshot, kept here so the
+// bloom pruner does not have to depend on the plugin package.
+func pruneMessageByID(messageID *hornet.MessageID) int {
+	cachedMsg := tangle.GetCachedMessageOrNil(messageID) // msg +1
+	if cachedMsg == nil {
+		return 0
+	}
+
+	cachedMsg.ConsumeMessage(func(msg *tangle.Message) { // msg -1
+		tangle.DeleteChild(msg.GetParent1MessageID(), msg.GetMessageID())
+		tangle.DeleteChild(msg.GetParent2MessageID(), msg.GetMessageID())
+		tangle.DeleteChildren(msg.GetMessageID())
+
+		if indexationPayload := tangle.CheckIfIndexation(msg); indexationPayload != nil {
+			tangle.DeleteIndexation(indexationPayload.Index, msg.GetMessageID())
+		}
+
+		tangle.DeleteMessage(msg.GetMessageID())
+	})
+
+	return 1
+}
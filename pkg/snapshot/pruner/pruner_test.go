@@ -0,0 +1,43 @@
+package pruner
+
+import (
+	"testing"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+func TestShouldPruneDuringSweepKeepsMessagesAtOrAfterTarget(t *testing.T) {
+	// Pass one only ever inserts IDs reachable from milestones
+	// pruningIndex+1..targetIndex, so anything confirmed at or after
+	// targetIndex is absent from the filter without being unreachable
+	// history: it simply hasn't been traversed yet, and must survive.
+	if shouldPruneDuringSweep(false, false, true, 100, 100) {
+		t.Error("a message confirmed exactly at targetIndex must survive the sweep")
+	}
+	if shouldPruneDuringSweep(false, false, true, 150, 100) {
+		t.Error("a message confirmed after targetIndex must survive the sweep")
+	}
+}
+
+func TestShouldPruneDuringSweepKeepsUnconfirmedMessages(t *testing.T) {
+	// Unconfirmed messages are current, still-live tangle state (new tips,
+	// etc.), not history to prune.
+	if shouldPruneDuringSweep(false, false, false, 0, 100) {
+		t.Error("an unconfirmed message must survive the sweep")
+	}
+}
+
+func TestShouldPruneDuringSweepKeepsSolidEntryPointsAndFilterHits(t *testing.T) {
+	if shouldPruneDuringSweep(true, false, true, 10, 100) {
+		t.Error("a solid entry point must survive the sweep regardless of its confirmation index")
+	}
+	if shouldPruneDuringSweep(false, true, true, 10, 100) {
+		t.Error("a message recognized by the bloom filter must survive the sweep")
+	}
+}
+
+func TestShouldPruneDuringSweepPrunesOldConfirmedMessages(t *testing.T) {
+	if !shouldPruneDuringSweep(false, false, true, 10, milestone.Index(100)) {
+		t.Error("a message confirmed before targetIndex, absent from the filter and not a solid entry point must be pruned")
+	}
+}
@@ -0,0 +1,147 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+// journalVersion is bumped whenever the on-disk journal format changes.
+// Nodes that find a journal with an older (or no) version simply discard it
+// and start pruning fresh instead of attempting a migration.
+const journalVersion = 1
+
+const pruningJournalFileName = "pruning_journal.json"
+
+// pruningPhase marks how far the current prune run got before the journal
+// entry was last written. Replaying the journal resumes at the recorded
+// phase instead of redoing work that was already flushed.
+type pruningPhase string
+
+const (
+	// pruningPhaseTraversing means the parent-cone traversal for the
+	// current target range may not have finished, so any previously
+	// recorded pending IDs cannot be trusted and nothing is replayed.
+	pruningPhaseTraversing pruningPhase = "traversing"
+	// pruningPhaseDeletingMessages means a batch of PendingMessageIDs was
+	// about to be deleted (together with their children edges and
+	// indexation entries) when the journal was last written.
+	pruningPhaseDeletingMessages pruningPhase = "deletingMessages"
+	pruningPhaseDone             pruningPhase = "done"
+)
+
+// pruningJournalEntry is written to disk before each batch of deletes is
+// flushed, so a crash mid-run can be recovered from without leaving
+// dangling children edges or orphan indexation entries behind.
+type pruningJournalEntry struct {
+	Version int `json:"version"`
+	// TargetIndex is the milestone index the in-progress prune run is
+	// working towards.
+	TargetIndex       milestone.Index `json:"targetIndex"`
+	PendingMessageIDs []string        `json:"pendingMessageIDs"`
+	Phase             pruningPhase    `json:"phase"`
+}
+
+// journalDirPath is the directory the pruning journal is written to. It
+// defaults to defaultSnapshotDir so the journal never silently lands in the
+// process's working directory, and can be overridden via ConfigureJournal.
+const defaultSnapshotDir = "snapshots"
+
+var journalDirPath = defaultSnapshotDir
+
+// ConfigureJournal sets the directory the pruning journal is persisted
+// under. It is called from ConfigurePruner, alongside the rest of the
+// pruning policy wiring; until then the journal is written under
+// defaultSnapshotDir.
+func ConfigureJournal(snapshotDir string) {
+	journalDirPath = snapshotDir
+}
+
+func journalFilePath() string {
+	return filepath.Join(journalDirPath, pruningJournalFileName)
+}
+
+// writeJournal persists the current pruning progress so it can be resumed
+// after a crash.
+func writeJournal(entry *pruningJournalEntry) error {
+	entry.Version = journalVersion
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal pruning journal")
+	}
+
+	if err := os.MkdirAll(journalDirPath, 0o777); err != nil {
+		return errors.Wrap(err, "failed to create pruning journal directory")
+	}
+
+	if err := os.WriteFile(journalFilePath(), data, 0o666); err != nil {
+		return errors.Wrap(err, "failed to write pruning journal")
+	}
+
+	return nil
+}
+
+// readJournal reads the pruning journal from disk. It returns nil, nil if
+// no journal exists (e.g. this is an old node upgrading, or the last prune
+// completed cleanly), and discards (rather than errors on) a journal
+// written by an incompatible version.
+func readJournal() (*pruningJournalEntry, error) {
+	data, err := os.ReadFile(journalFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read pruning journal")
+	}
+
+	entry := &pruningJournalEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal pruning journal")
+	}
+
+	if entry.Version != journalVersion {
+		log.Warnf("discarding pruning journal written by incompatible version %d (expected %d)", entry.Version, journalVersion)
+		return nil, nil
+	}
+
+	return entry, nil
+}
+
+func deleteJournal() error {
+	err := os.Remove(journalFilePath())
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to delete pruning journal")
+	}
+	return nil
+}
+
+// replayPruningJournal is called once before a prune run starts. If a
+// journal from an unclean shutdown is found, it idempotently finishes the
+// interrupted delete batch (pruneMessages silently skips anything already
+// gone) and marks the journal done.
+func replayPruningJournal() error {
+	entry, err := readJournal()
+	if err != nil {
+		return err
+	}
+	if entry == nil || entry.Phase == pruningPhaseDone {
+		return nil
+	}
+
+	log.Infof("resuming interrupted prune towards milestone %d from phase %q", entry.TargetIndex, entry.Phase)
+
+	if entry.Phase == pruningPhaseDeletingMessages && len(entry.PendingMessageIDs) > 0 {
+		messageIDsToDeleteMap := make(map[string]struct{}, len(entry.PendingMessageIDs))
+		for _, mapKey := range entry.PendingMessageIDs {
+			messageIDsToDeleteMap[mapKey] = struct{}{}
+		}
+		pruneMessages(messageIDsToDeleteMap)
+	}
+
+	return writeJournal(&pruningJournalEntry{TargetIndex: entry.TargetIndex, Phase: pruningPhaseDone})
+}
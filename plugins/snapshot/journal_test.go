@@ -0,0 +1,82 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+func TestJournalRoundTrip(t *testing.T) {
+	ConfigureJournal(t.TempDir())
+	defer ConfigureJournal(defaultSnapshotDir)
+
+	if entry, err := readJournal(); err != nil {
+		t.Fatalf("unexpected error reading missing journal: %v", err)
+	} else if entry != nil {
+		t.Fatalf("expected nil entry for missing journal, got %+v", entry)
+	}
+
+	written := &pruningJournalEntry{
+		TargetIndex:       milestone.Index(42),
+		PendingMessageIDs: []string{"a", "b", "c"},
+		Phase:             pruningPhaseDeletingMessages,
+	}
+	if err := writeJournal(written); err != nil {
+		t.Fatalf("failed to write journal: %v", err)
+	}
+
+	read, err := readJournal()
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+	if read == nil {
+		t.Fatal("expected journal entry, got nil")
+	}
+	if read.TargetIndex != written.TargetIndex {
+		t.Errorf("expected TargetIndex %d, got %d", written.TargetIndex, read.TargetIndex)
+	}
+	if read.Phase != written.Phase {
+		t.Errorf("expected Phase %q, got %q", written.Phase, read.Phase)
+	}
+	if len(read.PendingMessageIDs) != len(written.PendingMessageIDs) {
+		t.Fatalf("expected %d pending message IDs, got %d", len(written.PendingMessageIDs), len(read.PendingMessageIDs))
+	}
+
+	if err := deleteJournal(); err != nil {
+		t.Fatalf("failed to delete journal: %v", err)
+	}
+	if entry, err := readJournal(); err != nil {
+		t.Fatalf("unexpected error reading deleted journal: %v", err)
+	} else if entry != nil {
+		t.Fatalf("expected nil entry after delete, got %+v", entry)
+	}
+}
+
+func TestReadJournalDiscardsIncompatibleVersion(t *testing.T) {
+	ConfigureJournal(t.TempDir())
+	defer ConfigureJournal(defaultSnapshotDir)
+
+	if err := writeJournal(&pruningJournalEntry{TargetIndex: 1, Phase: pruningPhaseDone}); err != nil {
+		t.Fatalf("failed to write journal: %v", err)
+	}
+
+	// simulate an incompatible on-disk version by writing one directly
+	entry := &pruningJournalEntry{Version: journalVersion + 1, TargetIndex: 1, Phase: pruningPhaseDone}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if err := os.WriteFile(journalFilePath(), data, 0o666); err != nil {
+		t.Fatalf("failed to write raw journal: %v", err)
+	}
+
+	read, err := readJournal()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if read != nil {
+		t.Fatalf("expected incompatible journal to be discarded, got %+v", read)
+	}
+}
@@ -0,0 +1,174 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/model/hornet"
+	"github.com/gohornet/hornet/pkg/model/milestone"
+	"github.com/gohornet/hornet/pkg/model/tangle"
+	snapshotpkg "github.com/gohornet/hornet/pkg/snapshot"
+	tanglePlugin "github.com/gohornet/hornet/plugins/tangle"
+)
+
+// defaultBloomFilterSize sizes the bloom policy's filter for roughly ten
+// million reachable messages, comfortably above what a single catch-up
+// pruning range is expected to traverse.
+const defaultBloomFilterSize = 10_000_000
+
+// defaultSplitStoreRetainState is the number of milestones of history the
+// splitstore policy keeps reachable in the cold store after a compaction,
+// mirroring the depth policy's default keep-behind window.
+const defaultSplitStoreRetainState = 50000
+
+// configuredPruner is the node-wide Pruner built by ConfigurePruner from its
+// policy argument. It is reused for every prune run through
+// RunConfiguredPrune.
+var configuredPruner snapshotpkg.Pruner
+
+// ConfigurePruner builds the Pruner for policy ("archive", "depth", "size",
+// "time", "bloom" or "splitstore") and wires it to the existing pruning
+// machinery: the historical depth-based pruneDatabase, the database
+// directory for size accounting, the tangle plugin's
+// PruningMilestoneIndexChanged event and, for "splitstore", the SplitStore
+// opened on the node's real tangle store plus a cold archive under
+// databaseDir.
+func ConfigurePruner(policy string, databaseDir string, sizeTarget uint64, retentionPeriod time.Duration) error {
+	ConfigureJournal(databaseDir)
+
+	cfg := defaultPrunerConfig(databaseDir, sizeTarget, retentionPeriod)
+
+	if policy == "splitstore" {
+		splitStore, err := tangle.NewSplitStore(tangle.SplitStoreConfig{
+			ColdPath:    filepath.Join(databaseDir, "cold"),
+			GCMode:      tangle.GCModeOnline,
+			RetainState: defaultSplitStoreRetainState,
+
+			// Left nil on purpose: splitStorePolicyPruner fires
+			// PruningMilestoneIndexChanged itself via
+			// cfg.OnPruningMilestoneIndexChanged once Compact succeeds, so
+			// wiring it here too would fire it twice.
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to open splitstore")
+		}
+		cfg.SplitStore = splitStore
+		cfg.LatestSolidMilestoneIndexFunc = tangle.GetSolidMilestoneIndex
+	}
+
+	pruner, err := snapshotpkg.NewPruner(policy, cfg)
+	if err != nil {
+		return errors.Wrapf(err, "failed to configure pruning policy %q", policy)
+	}
+
+	configuredPruner = pruner
+	return nil
+}
+
+// defaultPrunerConfig builds the Config shared by every policy, wiring the
+// historical depth-based pruneDatabase, the database directory for size
+// accounting, and the tangle plugin's PruningMilestoneIndexChanged event.
+func defaultPrunerConfig(databaseDir string, sizeTarget uint64, retentionPeriod time.Duration) snapshotpkg.Config {
+	return snapshotpkg.Config{
+		DepthPruneFunc: func(ctx context.Context, target milestone.Index) error {
+			return pruneDatabase(target, ctx.Done())
+		},
+		DatabaseSizeFunc: func() (uint64, error) {
+			return directorySize(databaseDir)
+		},
+		MilestoneTimestampFunc: milestoneTimestamp,
+		PruningIndexFunc:       currentPruningIndex,
+		SizeTarget:             sizeTarget,
+		RetentionPeriod:        retentionPeriod,
+		OnPruningMilestoneIndexChanged: func(index milestone.Index) {
+			tanglePlugin.Events.PruningMilestoneIndexChanged.Trigger(index)
+		},
+		SnapshotDir:          databaseDir,
+		BloomFilterSize:      defaultBloomFilterSize,
+		SolidEntryPointsFunc: solidEntryPointsSnapshot,
+	}
+}
+
+// RunConfiguredPrune runs the node's configured Pruner up to target. If
+// ConfigurePruner was never called, it builds a plain "depth" Pruner on the
+// fly instead of calling pruneDatabase directly, so
+// PruningMilestoneIndexChanged still fires exactly once, from the policy
+// layer, even without explicit policy configuration.
+func RunConfiguredPrune(target milestone.Index, abortSignal <-chan struct{}) error {
+	activePruner := configuredPruner
+	if activePruner == nil {
+		var err error
+		activePruner, err = snapshotpkg.NewPruner("depth", defaultPrunerConfig("", 0, 0))
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-abortSignal:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return activePruner.Prune(ctx, target)
+}
+
+func currentPruningIndex() milestone.Index {
+	snapshotInfo := tangle.GetSnapshotInfo()
+	if snapshotInfo == nil {
+		return 0
+	}
+	return snapshotInfo.PruningIndex
+}
+
+func milestoneTimestamp(index milestone.Index) (time.Time, error) {
+	cachedMs := tangle.GetCachedMilestoneOrNil(index) // milestone +1
+	if cachedMs == nil {
+		return time.Time{}, errors.Errorf("milestone %d not found", index)
+	}
+	defer cachedMs.Release(true) // milestone -1
+
+	return cachedMs.GetMilestone().Timestamp, nil
+}
+
+// solidEntryPointsSnapshot reads the currently stored solid entry points
+// into a MapKey-keyed set, the form the bloom policy needs to check a
+// candidate message against before deleting it.
+func solidEntryPointsSnapshot() (map[string]struct{}, error) {
+	solidEntryPoints := make(map[string]struct{})
+
+	tangle.ReadLockSolidEntryPoints()
+	defer tangle.ReadUnlockSolidEntryPoints()
+
+	tangle.ForEachSolidEntryPoint(func(solidEntryPointMessageID *hornet.MessageID, _ milestone.Index) bool {
+		solidEntryPoints[solidEntryPointMessageID.MapKey()] = struct{}{}
+		return true
+	})
+
+	return solidEntryPoints, nil
+}
+
+func directorySize(dir string) (uint64, error) {
+	var size uint64
+
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+
+	return size, err
+}
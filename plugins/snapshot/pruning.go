@@ -5,13 +5,11 @@ import (
 
 	"github.com/pkg/errors"
 
-	"github.com/gohornet/hornet/pkg/dag"
 	"github.com/gohornet/hornet/pkg/model/hornet"
 	"github.com/gohornet/hornet/pkg/model/milestone"
 	"github.com/gohornet/hornet/pkg/model/tangle"
 	"github.com/gohornet/hornet/pkg/model/utxo"
 	"github.com/gohornet/hornet/plugins/database"
-	tanglePlugin "github.com/gohornet/hornet/plugins/tangle"
 )
 
 const (
@@ -134,6 +132,10 @@ func pruneDatabase(targetIndex milestone.Index, abortSignal <-chan struct{}) err
 	setIsPruning(true)
 	defer setIsPruning(false)
 
+	if err := replayPruningJournal(); err != nil {
+		return errors.Wrap(err, "failed to replay pruning journal")
+	}
+
 	// calculate solid entry points for the new end of the tangle history
 	tangle.WriteLockSolidEntryPoints()
 	tangle.ResetSolidEntryPoints()
@@ -156,74 +158,54 @@ func pruneDatabase(targetIndex milestone.Index, abortSignal <-chan struct{}) err
 	// unconfirmed msgs have to be pruned for PruningIndex as well, since this could be LSI at startup of the node
 	pruneUnconfirmedMessages(snapshotInfo.PruningIndex)
 
-	// Iterate through all milestones that have to be pruned
-	for milestoneIndex := snapshotInfo.PruningIndex + 1; milestoneIndex <= targetIndex; milestoneIndex++ {
+	pruningIndex := snapshotInfo.PruningIndex
+
+	log.Infof("Pruning milestones (%d-%d)...", pruningIndex+1, targetIndex)
+	ts := time.Now()
+
+	var txCountDeleted, msgCountChecked int
+	for milestoneIndex := pruningIndex + 1; milestoneIndex <= targetIndex; milestoneIndex++ {
 		select {
 		case <-abortSignal:
-			// Stop pruning the next milestone
 			return ErrPruningAborted
 		default:
 		}
 
-		log.Infof("Pruning milestone (%d)...", milestoneIndex)
-
-		ts := time.Now()
-		txCountDeleted, msgCountChecked := pruneUnconfirmedMessages(milestoneIndex)
-
-		cachedMs := tangle.GetCachedMilestoneOrNil(milestoneIndex) // milestone +1
-		if cachedMs == nil {
-			// Milestone not found, pruning impossible
-			log.Warnf("Pruning milestone (%d) failed! Milestone not found!", milestoneIndex)
-			continue
-		}
+		deleted, checked := pruneUnconfirmedMessages(milestoneIndex)
+		txCountDeleted += deleted
+		msgCountChecked += checked
+	}
 
-		messageIDsToDeleteMap := make(map[string]struct{})
-
-		err := dag.TraverseParents(cachedMs.GetMilestone().MessageID,
-			// traversal stops if no more messages pass the given condition
-			// Caution: condition func is not in DFS order
-			func(cachedMsgMeta *tangle.CachedMetadata) (bool, error) { // msg +1
-				defer cachedMsgMeta.Release(true) // msg -1
-				// everything that was referenced by that milestone can be pruned (even messages of older milestones)
-				return true, nil
-			},
-			// consumer
-			func(cachedMsgMeta *tangle.CachedMetadata) error { // msg +1
-				defer cachedMsgMeta.Release(true) // msg -1
-				messageIDsToDeleteMap[cachedMsgMeta.GetMetadata().GetMessageID().MapKey()] = struct{}{}
-				return nil
-			},
-			// called on missing parents
-			func(parentMessageID *hornet.MessageID) error { return nil },
-			// called on solid entry points
-			// Ignore solid entry points (snapshot milestone included)
-			nil,
-			// the pruning target index is also a solid entry point => traverse it anyways
-			true,
-			nil)
-
-		cachedMs.Release(true) // milestone -1
-		if err != nil {
-			log.Warnf("Pruning milestone (%d) failed! Error: %v", milestoneIndex, err)
-			continue
-		}
+	if err := writeJournal(&pruningJournalEntry{TargetIndex: targetIndex, Phase: pruningPhaseTraversing}); err != nil {
+		return err
+	}
 
-		err = pruneMilestone(milestoneIndex)
-		if err != nil {
-			log.Warnf("Pruning milestone (%d) failed! %v", err.Error())
-		}
+	// traverse the parent cones of all milestones in the range concurrently, deduplicating
+	// discovered message IDs and flushing their deletion (together with their children edges
+	// and indexation entries) in batches instead of once per message. Each batch is journaled
+	// with its actual pending IDs before it is flushed, so a crash mid-run can be recovered
+	// from without redoing already-flushed batches.
+	msgCountDeleted, err := pruneMilestoneRangeConcurrent(pruningIndex, targetIndex, PruneConcurrencyConfig{}, abortSignal)
+	if err != nil {
+		return err
+	}
 
-		msgCountChecked += len(messageIDsToDeleteMap)
-		txCountDeleted += pruneMessages(messageIDsToDeleteMap)
+	if err := writeJournal(&pruningJournalEntry{TargetIndex: targetIndex, Phase: pruningPhaseDone}); err != nil {
+		return err
+	}
 
-		snapshotInfo.PruningIndex = milestoneIndex
-		tangle.SetSnapshotInfo(snapshotInfo)
+	msgCountChecked += msgCountDeleted
+	txCountDeleted += msgCountDeleted
 
-		log.Infof("Pruning milestone (%d) took %v. Pruned %d/%d messages. ", milestoneIndex, time.Since(ts), txCountDeleted, msgCountChecked)
+	snapshotInfo.PruningIndex = targetIndex
+	tangle.SetSnapshotInfo(snapshotInfo)
 
-		tanglePlugin.Events.PruningMilestoneIndexChanged.Trigger(milestoneIndex)
-	}
+	log.Infof("Pruning milestones (%d-%d) took %v. Pruned %d/%d messages. ", pruningIndex+1, targetIndex, time.Since(ts), txCountDeleted, msgCountChecked)
 
+	// PruningMilestoneIndexChanged is not triggered here: the depth, size
+	// and time policies in pkg/snapshot, which reach pruneDatabase through
+	// DepthPruneFunc, fire it once pruneDatabase returns successfully, so it
+	// fires exactly once per pruneDatabase call made through them.
 	database.RunGarbageCollection()
 
 	return nil
@@ -0,0 +1,281 @@
+package snapshot
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/gohornet/hornet/pkg/dag"
+	"github.com/gohornet/hornet/pkg/model/hornet"
+	"github.com/gohornet/hornet/pkg/model/milestone"
+	"github.com/gohornet/hornet/pkg/model/tangle"
+)
+
+// defaultPruneBatchSize is the number of distinct messages queued into one
+// tangle.MessageDeletionBatch before it is flushed. Each message's children
+// edges and indexation entry are deleted along with it and do not count
+// towards the batch size.
+const defaultPruneBatchSize = 10000
+
+// PruneConcurrencyConfig tunes the parallel traversal and batched deletion
+// used by pruneMilestoneRangeConcurrent.
+type PruneConcurrencyConfig struct {
+	// WorkerCount is the number of milestones traversed in parallel. If
+	// zero, runtime.NumCPU() is used.
+	WorkerCount int
+
+	// BatchSize is the number of distinct messages per flushed
+	// tangle.MessageDeletionBatch. If zero, defaultPruneBatchSize is used.
+	BatchSize int
+}
+
+func (cfg PruneConcurrencyConfig) withDefaults() PruneConcurrencyConfig {
+	if cfg.WorkerCount <= 0 {
+		cfg.WorkerCount = runtime.NumCPU()
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultPruneBatchSize
+	}
+	return cfg
+}
+
+// pruneMilestoneRangeConcurrent traverses every milestone in
+// [pruningIndex+1, targetIndex] with a worker pool of cfg.WorkerCount
+// goroutines, deduplicating discovered message IDs through a shared
+// channel, and deletes them in batches of cfg.BatchSize rather than one
+// message at a time. It returns the total number of messages deleted. If
+// abortSignal is closed, it returns ErrPruningAborted and deletes no
+// milestone records.
+func pruneMilestoneRangeConcurrent(pruningIndex, targetIndex milestone.Index, cfg PruneConcurrencyConfig, abortSignal <-chan struct{}) (int, error) {
+	cfg = cfg.withDefaults()
+
+	milestoneIndexes := make(chan milestone.Index)
+	discoveredMessageIDs := make(chan *hornet.MessageID, cfg.BatchSize)
+
+	var traverseWg sync.WaitGroup
+	var traverseErr error
+	var traverseErrOnce sync.Once
+
+	// stopFeeding is closed the first time a worker gives up on a
+	// traversal error (as opposed to abortSignal, which is closed by the
+	// caller). Without it the milestone-feeding goroutine below would
+	// keep sending on milestoneIndexes long after every worker has
+	// returned, deadlocking pruneDatabase instead of surfacing
+	// traverseErr.
+	stopFeeding := make(chan struct{})
+	var stopFeedingOnce sync.Once
+
+	var traversedMu sync.Mutex
+	var traversedMilestones []milestone.Index
+
+	abortTraversal := func(err error) {
+		traverseErrOnce.Do(func() {
+			traverseErr = err
+		})
+		stopFeedingOnce.Do(func() {
+			close(stopFeeding)
+		})
+	}
+
+	for i := 0; i < cfg.WorkerCount; i++ {
+		traverseWg.Add(1)
+		go func() {
+			defer traverseWg.Done()
+
+			for milestoneIndex := range milestoneIndexes {
+				select {
+				case <-abortSignal:
+					return
+				default:
+				}
+
+				cachedMs := tangle.GetCachedMilestoneOrNil(milestoneIndex) // milestone +1
+				if cachedMs == nil {
+					log.Warnf("Pruning milestone (%d) failed! Milestone not found!", milestoneIndex)
+					continue
+				}
+				milestoneMessageID := cachedMs.GetMilestone().MessageID
+				cachedMs.Release(true) // milestone -1
+
+				err := dag.TraverseParents(milestoneMessageID,
+					func(cachedMsgMeta *tangle.CachedMetadata) (bool, error) { // msg +1
+						defer cachedMsgMeta.Release(true) // msg -1
+						return true, nil
+					},
+					func(cachedMsgMeta *tangle.CachedMetadata) error { // msg +1
+						defer cachedMsgMeta.Release(true) // msg -1
+						// Select on abortSignal too: once
+						// dedupAndBatchDelete has returned on abort and
+						// stopped draining discoveredMessageIDs, a plain
+						// send here would block forever.
+						select {
+						case discoveredMessageIDs <- cachedMsgMeta.GetMetadata().GetMessageID():
+						case <-abortSignal:
+						}
+						return nil
+					},
+					func(parentMessageID *hornet.MessageID) error { return nil },
+					nil,
+					true,
+					abortSignal)
+				if err != nil {
+					abortTraversal(err)
+					return
+				}
+
+				// The milestone record itself is only deleted once its
+				// messages are confirmed flushed by dedupAndBatchDelete
+				// below. Deleting it here, before the messages it lets us
+				// rediscover are actually gone, would mean a crash in
+				// between leaves those messages permanently orphaned: a
+				// restarted prune looks the milestone up to retraverse its
+				// cone, finds nothing, and skips it.
+				traversedMu.Lock()
+				traversedMilestones = append(traversedMilestones, milestoneIndex)
+				traversedMu.Unlock()
+			}
+		}()
+	}
+
+	go func() {
+		// Defers run in reverse order: milestoneIndexes is closed first
+		// so every worker's range loop can drain and return, then
+		// traverseWg.Wait() blocks until they actually have, and only
+		// then is discoveredMessageIDs closed. Closing it any earlier
+		// would race a still-running worker's send into it, panicking
+		// with "send on closed channel" on any abort or traversal error
+		// that lands mid-prune.
+		defer close(discoveredMessageIDs)
+		defer traverseWg.Wait()
+		defer close(milestoneIndexes)
+
+		for milestoneIndex := pruningIndex + 1; milestoneIndex <= targetIndex; milestoneIndex++ {
+			select {
+			case <-abortSignal:
+				return
+			case <-stopFeeding:
+				return
+			case milestoneIndexes <- milestoneIndex:
+			}
+		}
+	}()
+
+	msgCountDeleted, err := dedupAndBatchDelete(discoveredMessageIDs, targetIndex, cfg.BatchSize, abortSignal)
+	if err != nil {
+		return msgCountDeleted, err
+	}
+
+	// Workers and the feeding goroutine stop on abortSignal without an
+	// error, so an abort that dedupAndBatchDelete did not observe would
+	// otherwise read as a fully traversed range.
+	select {
+	case <-abortSignal:
+		return msgCountDeleted, ErrPruningAborted
+	default:
+	}
+
+	// Only now that every discovered message has been flushed is it safe to
+	// drop the milestone records themselves.
+	traversedMu.Lock()
+	for _, milestoneIndex := range traversedMilestones {
+		if err := pruneMilestone(milestoneIndex); err != nil {
+			log.Warnf("Pruning milestone (%d) failed! %v", milestoneIndex, err)
+		}
+	}
+	traversedMu.Unlock()
+
+	return msgCountDeleted, traverseErr
+}
+
+// dedupAndBatchDelete consumes message IDs from discoveredMessageIDs,
+// dropping duplicates, and flushes a tangle.MessageDeletionBatch covering
+// each message, its children edges and its indexation entry every time
+// batchSize distinct IDs have accumulated (or the channel is drained). The
+// batch deletes message by message through the tangle helpers; it is not a
+// single badger WriteBatch. Each batch is journaled with its actual pending
+// IDs before it is flushed, so after a crash mid-batch replayPruningJournal
+// deletes exactly those IDs on the next run, before that run traverses the
+// range again. Progress is logged so operators can tune batch size against
+// observed throughput.
+func dedupAndBatchDelete(discoveredMessageIDs <-chan *hornet.MessageID, targetIndex milestone.Index, batchSize int, abortSignal <-chan struct{}) (int, error) {
+	seen := make(map[string]struct{})
+	pending := make([]*hornet.MessageID, 0, batchSize)
+
+	msgCountDeleted := 0
+	batchesFlushed := 0
+	ts := time.Now()
+
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+
+		pendingMapKeys := make([]string, 0, len(pending))
+		for _, messageID := range pending {
+			pendingMapKeys = append(pendingMapKeys, messageID.MapKey())
+		}
+		if err := writeJournal(&pruningJournalEntry{TargetIndex: targetIndex, PendingMessageIDs: pendingMapKeys, Phase: pruningPhaseDeletingMessages}); err != nil {
+			return err
+		}
+
+		batch := tangle.NewMessageDeletionBatch()
+		for _, messageID := range pending {
+			batch.QueueMessage(messageID)
+		}
+		if err := batch.Flush(); err != nil {
+			// The journal entry written above still names these IDs as
+			// pending, so a restarted prune retries this exact batch
+			// instead of silently treating a failed delete as done.
+			return errors.Wrap(err, "failed to flush pruning batch")
+		}
+
+		msgCountDeleted += len(pending)
+		batchesFlushed++
+		pending = pending[:0]
+
+		if err := writeJournal(&pruningJournalEntry{TargetIndex: targetIndex, Phase: pruningPhaseDone}); err != nil {
+			return err
+		}
+
+		elapsed := time.Since(ts).Seconds()
+		messagesPerSecond := float64(0)
+		if elapsed > 0 {
+			messagesPerSecond = float64(msgCountDeleted) / elapsed
+		}
+		log.Infof("Pruning batch #%d flushed. %d messages deleted, %.1f msg/s", batchesFlushed, msgCountDeleted, messagesPerSecond)
+
+		return nil
+	}
+
+	for messageID := range discoveredMessageIDs {
+		select {
+		case <-abortSignal:
+			if err := flush(); err != nil {
+				return msgCountDeleted, err
+			}
+			return msgCountDeleted, ErrPruningAborted
+		default:
+		}
+
+		mapKey := messageID.MapKey()
+		if _, exists := seen[mapKey]; exists {
+			continue
+		}
+		seen[mapKey] = struct{}{}
+
+		pending = append(pending, messageID)
+		if len(pending) >= batchSize {
+			if err := flush(); err != nil {
+				return msgCountDeleted, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return msgCountDeleted, err
+	}
+
+	return msgCountDeleted, nil
+}
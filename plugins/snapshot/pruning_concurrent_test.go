@@ -0,0 +1,143 @@
+package snapshot
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gohornet/hornet/pkg/model/hornet"
+	"github.com/gohornet/hornet/pkg/model/milestone"
+)
+
+func TestPruneConcurrencyConfigWithDefaults(t *testing.T) {
+	cfg := PruneConcurrencyConfig{}.withDefaults()
+	if cfg.WorkerCount != runtime.NumCPU() {
+		t.Errorf("expected WorkerCount to default to runtime.NumCPU() (%d), got %d", runtime.NumCPU(), cfg.WorkerCount)
+	}
+	if cfg.BatchSize != defaultPruneBatchSize {
+		t.Errorf("expected BatchSize to default to %d, got %d", defaultPruneBatchSize, cfg.BatchSize)
+	}
+
+	cfg = PruneConcurrencyConfig{WorkerCount: 4, BatchSize: 500}.withDefaults()
+	if cfg.WorkerCount != 4 {
+		t.Errorf("expected explicit WorkerCount 4 to be preserved, got %d", cfg.WorkerCount)
+	}
+	if cfg.BatchSize != 500 {
+		t.Errorf("expected explicit BatchSize 500 to be preserved, got %d", cfg.BatchSize)
+	}
+}
+
+// fakeMessageID builds a distinct message ID from a single distinguishing
+// byte, without a real message behind it: dedupAndBatchDelete dedupes and
+// counts IDs by MapKey(), and the tangle.MessageDeletionBatch it flushes
+// looks each ID up with tangle.GetCachedMessageOrNil and skips IDs with no
+// stored message.
+func fakeMessageID(t *testing.T, distinguisher byte) *hornet.MessageID {
+	t.Helper()
+	return hornet.MessageIDFromMapKey(strings.Repeat(string(distinguisher), 32))
+}
+
+func TestDedupAndBatchDeleteDedupesAcrossBatchBoundaries(t *testing.T) {
+	ConfigureJournal(t.TempDir())
+	defer ConfigureJournal(defaultSnapshotDir)
+
+	a := fakeMessageID(t, 'a')
+	b := fakeMessageID(t, 'b')
+
+	discovered := make(chan *hornet.MessageID, 3)
+	discovered <- a
+	discovered <- a
+	discovered <- b
+	close(discovered)
+
+	// batchSize 1 forces a flush after every distinct ID, so this also
+	// proves seen persists across flushes: the duplicate of a arriving in
+	// what would be its own batch must not be recounted.
+	count, err := dedupAndBatchDelete(discovered, milestone.Index(100), 1, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 distinct messages deleted, got %d", count)
+	}
+}
+
+func TestDedupAndBatchDeleteFlushesAtBatchBoundaries(t *testing.T) {
+	ConfigureJournal(t.TempDir())
+	defer ConfigureJournal(defaultSnapshotDir)
+
+	ids := []*hornet.MessageID{
+		fakeMessageID(t, 'a'),
+		fakeMessageID(t, 'b'),
+		fakeMessageID(t, 'c'),
+		fakeMessageID(t, 'd'),
+		fakeMessageID(t, 'e'),
+	}
+
+	discovered := make(chan *hornet.MessageID, len(ids))
+	for _, id := range ids {
+		discovered <- id
+	}
+	close(discovered)
+
+	// batchSize 2 against 5 distinct IDs forces two full batches plus a
+	// trailing partial one; every ID must still end up counted regardless
+	// of where the boundary falls.
+	count, err := dedupAndBatchDelete(discovered, milestone.Index(100), 2, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != len(ids) {
+		t.Errorf("expected %d messages deleted across batch boundaries, got %d", len(ids), count)
+	}
+}
+
+func TestDedupAndBatchDeleteAbortsMidStream(t *testing.T) {
+	ConfigureJournal(t.TempDir())
+	defer ConfigureJournal(defaultSnapshotDir)
+
+	a := fakeMessageID(t, 'a')
+	b := fakeMessageID(t, 'b')
+
+	discovered := make(chan *hornet.MessageID) // unbuffered: sends synchronize with processing
+	abortSignal := make(chan struct{})
+
+	type result struct {
+		count int
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		// batchSize large enough that nothing flushes on its own; only
+		// the abort path should trigger a flush here.
+		count, err := dedupAndBatchDelete(discovered, milestone.Index(100), 100, abortSignal)
+		done <- result{count, err}
+	}()
+
+	// An unbuffered send returns as soon as the loop has received, not once
+	// it has processed the value. Sending a a second time therefore only
+	// returns after the loop has come back around to receive, i.e. after the
+	// first a has been appended to pending; the duplicate itself is dropped
+	// as already seen or, if abortSignal is closed by then, never looked at.
+	discovered <- a
+	discovered <- a
+
+	close(abortSignal)
+
+	// The loop has either already returned (it saw abortSignal while
+	// handling the duplicate) or is waiting to receive; in the latter case b
+	// unblocks it, it finds abortSignal closed before b is ever appended,
+	// and returns without consuming it.
+	var res result
+	select {
+	case discovered <- b:
+		res = <-done
+	case res = <-done:
+	}
+	if res.err != ErrPruningAborted {
+		t.Fatalf("expected ErrPruningAborted, got %v", res.err)
+	}
+	if res.count != 1 {
+		t.Errorf("expected only the already-pending message (a) to be flushed before aborting, got count %d", res.count)
+	}
+}